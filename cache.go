@@ -0,0 +1,197 @@
+package tasker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+)
+
+// TaskOutput is the result produced by a CacheableTask. It is stored and
+// retrieved opaquely; a Cache and the tasks that share it should agree out
+// of band on its structure.
+type TaskOutput []byte
+
+// CacheKey is caller-provided input describing what a cacheable task's
+// result depends on, e.g. a hash of its input files or environment. It is
+// combined with the task's name and its dependencies' output hashes to form
+// the key a Cache is queried and populated with. A nil or empty CacheKey is
+// valid; the task is still keyed by its name and its dependencies' outputs.
+type CacheKey []byte
+
+// A CacheableTask is like a ContextTask, but returns a TaskOutput alongside
+// its error. On success, the output is stored in the Tasker's Cache (if one
+// has been set with UseCache) and made available to dependents through
+// Output.
+type CacheableTask func(ctx context.Context) (TaskOutput, error)
+
+// Cache stores TaskOutputs keyed by the composite key AddCacheable computes
+// for a task. Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the output stored under key, and whether it was found.
+	Get(key string) (TaskOutput, bool)
+
+	// Set stores output under key.
+	Set(key string, output TaskOutput) error
+}
+
+// MemoryCache is a Cache backed by an in-memory map. It does not persist
+// across process restarts.
+type MemoryCache struct {
+	mux  sync.Mutex
+	data map[string]TaskOutput
+}
+
+// NewMemoryCache returns an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{sync.Mutex{}, make(map[string]TaskOutput)}
+}
+
+func (c *MemoryCache) Get(key string) (TaskOutput, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	out, ok := c.data[key]
+	return out, ok
+}
+
+func (c *MemoryCache) Set(key string, output TaskOutput) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.data[key] = output
+	return nil
+}
+
+// FileCache is a Cache backed by a single JSON file on disk, rewritten in
+// full on every Set. It is meant for small caches used across process runs,
+// not high-throughput use.
+type FileCache struct {
+	path string
+	mux  sync.Mutex
+	data map[string]TaskOutput
+}
+
+// NewFileCache returns a FileCache backed by path, loading any entries
+// already stored there. A missing file is treated as an empty cache.
+func NewFileCache(path string) (*FileCache, error) {
+	c := &FileCache{path, sync.Mutex{}, make(map[string]TaskOutput)}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &c.data); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *FileCache) Get(key string) (TaskOutput, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	out, ok := c.data[key]
+	return out, ok
+}
+
+func (c *FileCache) Set(key string, output TaskOutput) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.data[key] = output
+
+	b, err := json.Marshal(c.data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, b, 0644)
+}
+
+// UseCache sets the Cache consulted and populated by tasks added through
+// AddCacheable. It must be called before Run or RunContext.
+func (tr *Tasker) UseCache(c Cache) {
+	tr.cache = c
+}
+
+// Output returns the TaskOutput published by the CacheableTask named name,
+// and whether one has been published yet. It is meant to be called from
+// within a CacheableTask to read the result of one of its dependencies,
+// after which the dependency is guaranteed to have finished running (or to
+// have been served from cache).
+func (tr *Tasker) Output(name string) (TaskOutput, bool) {
+	ti, ok := tr.tis[name]
+	if !ok {
+		return nil, false
+	}
+	return ti.output, ti.has_output
+}
+
+// store_output records the TaskOutput published by the task named name.
+func (tr *Tasker) store_output(name string, output TaskOutput) {
+	ti := tr.tis[name]
+	ti.output = output
+	ti.has_output = true
+}
+
+// composite_key computes the cache key for the task named name: the SHA-256
+// of the task's name, the sorted output hashes of its dependencies that have
+// themselves published a TaskOutput, and key.
+func (tr *Tasker) composite_key(name string, deps []string, key CacheKey) string {
+	dep_hashes := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		if out, ok := tr.Output(dep); ok {
+			sum := sha256.Sum256(out)
+			dep_hashes = append(dep_hashes, hex.EncodeToString(sum[:]))
+		}
+	}
+	sort.Strings(dep_hashes)
+
+	h := sha256.New()
+	h.Write([]byte(name))
+	for _, dep_hash := range dep_hashes {
+		h.Write([]byte(dep_hash))
+	}
+	h.Write(key)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// AddCacheable adds a cacheable task to a Tasker. name and deps behave as in
+// AddWithOptions; the task is registered with empty TaskOptions, so it runs
+// with no Timeout and no Retry. task's return value is cached keyed by
+// composite_key: if UseCache has not been called, the task always
+// runs; otherwise a cache hit skips running task and publishes the stored
+// TaskOutput instead.
+//
+// Like Add, AddCacheable returns an error if name is not unique.
+func (tr *Tasker) AddCacheable(name string, deps []string, task CacheableTask, key CacheKey) error {
+	ctx_task := func(ctx context.Context) error {
+		ck := tr.composite_key(name, deps, key)
+
+		if tr.cache != nil {
+			if out, ok := tr.cache.Get(ck); ok {
+				tr.store_output(name, out)
+				return nil
+			}
+		}
+
+		out, err := task(ctx)
+		if err != nil {
+			return err
+		}
+		tr.store_output(name, out)
+
+		if tr.cache != nil {
+			return tr.cache.Set(ck, out)
+		}
+		return nil
+	}
+
+	if err := tr.AddWithOptions(name, deps, ctx_task, TaskOptions{}); err != nil {
+		return err
+	}
+	tr.tis[name].cacheable = true
+	return nil
+}
@@ -0,0 +1,126 @@
+package tasker
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddCacheableMissThenHit(t *testing.T) {
+	cache := NewMemoryCache()
+	runs := 0
+
+	new_tasker := func() *Tasker {
+		tr, err := NewTasker(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr.UseCache(cache)
+		task := func(ctx context.Context) (TaskOutput, error) {
+			runs++
+			return TaskOutput("result"), nil
+		}
+		if err := tr.AddCacheable("foo", nil, task, CacheKey("input-v1")); err != nil {
+			t.Fatal(err)
+		}
+		return tr
+	}
+
+	tr := new_tasker()
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if out, ok := tr.Output("foo"); !ok || string(out) != "result" {
+		t.Fatalf("unexpected output: %q, %v", out, ok)
+	}
+	if runs != 1 {
+		t.Fatalf("expected 1 run, got %d", runs)
+	}
+
+	// A fresh Tasker sharing the same Cache should hit it instead of
+	// running the task again.
+	tr2 := new_tasker()
+	if err := tr2.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if out, ok := tr2.Output("foo"); !ok || string(out) != "result" {
+		t.Fatalf("unexpected output: %q, %v", out, ok)
+	}
+	if runs != 1 {
+		t.Fatalf("expected cache hit to skip task, got %d runs", runs)
+	}
+}
+
+func TestAddCacheableKeyChangeMisses(t *testing.T) {
+	cache := NewMemoryCache()
+	runs := 0
+
+	run := func(key CacheKey) {
+		tr, err := NewTasker(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr.UseCache(cache)
+		task := func(ctx context.Context) (TaskOutput, error) {
+			runs++
+			return TaskOutput("result"), nil
+		}
+		if err := tr.AddCacheable("foo", nil, task, key); err != nil {
+			t.Fatal(err)
+		}
+		if err := tr.Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run(CacheKey("v1"))
+	run(CacheKey("v2"))
+	if runs != 2 {
+		t.Fatalf("expected a cache miss after the key changed, got %d runs", runs)
+	}
+}
+
+func TestOutputReportsOkForNilOutput(t *testing.T) {
+	tr, err := NewTasker(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := func(ctx context.Context) (TaskOutput, error) {
+		return nil, nil
+	}
+	if err := tr.AddCacheable("foo", nil, task, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, ok := tr.Output("foo")
+	if !ok {
+		t.Fatal("expected ok, got false for a task that finished with a nil output")
+	}
+	if out != nil {
+		t.Fatalf("expected nil output, got %q", out)
+	}
+}
+
+func TestFileCachePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	c1, err := NewFileCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c1.Set("k", TaskOutput("v")); err != nil {
+		t.Fatal(err)
+	}
+
+	c2, err := NewFileCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, ok := c2.Get("k")
+	if !ok || string(out) != "v" {
+		t.Fatalf("unexpected output: %q, %v", out, ok)
+	}
+}
@@ -1,29 +1,27 @@
 package tasker
 
 import (
+	"errors"
 	"fmt"
 )
 
+// ErrCanceled is the error recorded against a task once the context.Context
+// passed to RunContext is done: a task skipped because the context was done
+// before it started, a task that returned an error while the context was
+// done, and every dependent skipped because of either.
+var ErrCanceled = errors.New("tasker: canceled")
 
-type CycleError [][]string
+// CycleError describes a single cycle found among a Tasker's tasks, as the
+// sequence of task names along an actual dependency edge path, starting and
+// ending on the same name, e.g. ["a", "b", "c", "a"].
+type CycleError []string
 
 func (ce CycleError) Error() string {
-	msg := "tasker: "
-	if len(ce) > 1 {
-		msg += "cycles"
-	} else {
-		msg += "cycle"
-	}
-	msg += " detected: "
-	for i, c := range ce {
-		for j, e := range c {
-			msg += e
-			if j < len(c) - 1 {
-				msg += " -> "
-			}
-		}
-		if i < len(ce) - 1 {
-			msg += ", "
+	msg := "tasker: cycle detected: "
+	for i, name := range ce {
+		msg += name
+		if i < len(ce)-1 {
+			msg += " -> "
 		}
 	}
 	return msg
@@ -41,3 +39,42 @@ func NewDepNotFoundError(v, w string) *DepNotFoundError {
 func (dnfe *DepNotFoundError) Error() string {
 	return fmt.Sprintf("tasker: %s not found, required by %s", dnfe.w, dnfe.v)
 }
+
+// UnsatisfiedDepError is returned by RunDownstream when a selected task
+// depends on a task outside the downstream selection that has not already
+// completed successfully, so there is nothing for RunDownstream to treat as
+// satisfied.
+type UnsatisfiedDepError struct {
+	v string
+	w string
+}
+
+func NewUnsatisfiedDepError(v, w string) *UnsatisfiedDepError {
+	return &UnsatisfiedDepError{v, w}
+}
+
+func (ude *UnsatisfiedDepError) Error() string {
+	return fmt.Sprintf("tasker: %s has not completed successfully, required by %s", ude.w, ude.v)
+}
+
+// ValidationError is returned by verify when a Tasker's task graph has one
+// or more problems: missing dependencies (DepNotFoundError) and/or cycles
+// (CycleError). Unlike a single error, it reports every problem found in one
+// pass rather than just the first.
+type ValidationError struct {
+	errs []error
+}
+
+func (ve *ValidationError) Error() string {
+	msg := "tasker: invalid task graph:"
+	for _, err := range ve.errs {
+		msg += "\n  " + err.Error()
+	}
+	return msg
+}
+
+// Unwrap exposes the individual DepNotFoundErrors and CycleErrors that make
+// up ve, so callers can inspect or match on them with errors.As.
+func (ve *ValidationError) Unwrap() []error {
+	return ve.errs
+}
@@ -0,0 +1,112 @@
+package tasker
+
+import (
+	"time"
+)
+
+// TaskEventType identifies the kind of status change a TaskEvent reports.
+type TaskEventType int
+
+const (
+	// EventQueued is emitted once a task's dependencies (if any) have all
+	// finished and it is queued for a worker, before EventStarted (or before
+	// EventSkipped, if the run's context is done by the time a worker takes
+	// it). A task skipped because a dependency failed also gets an
+	// EventQueued, immediately followed by its EventSkipped.
+	EventQueued TaskEventType = iota
+
+	// EventStarted is emitted immediately before a task's function is
+	// called.
+	EventStarted
+
+	// EventSucceeded is emitted after a task's function returns a nil
+	// error.
+	EventSucceeded
+
+	// EventFailed is emitted after a task's function returns a non-nil
+	// error.
+	EventFailed
+
+	// EventSkipped is emitted for a task whose function does not run on
+	// this call, because a dependency failed, the run's context was done,
+	// or the task already completed in an earlier call and needs no rerun
+	// (in that last case without a preceding EventQueued). Reason
+	// describes why.
+	EventSkipped
+)
+
+func (et TaskEventType) String() string {
+	switch et {
+	case EventQueued:
+		return "queued"
+	case EventStarted:
+		return "started"
+	case EventSucceeded:
+		return "succeeded"
+	case EventFailed:
+		return "failed"
+	case EventSkipped:
+		return "skipped"
+	default:
+		return "unknown"
+	}
+}
+
+// A TaskEvent reports a status change for a single task. Duration is only
+// set on EventSucceeded and EventFailed; Err is only set on EventFailed;
+// Reason is only set on EventSkipped.
+type TaskEvent struct {
+	Task     string
+	Type     TaskEventType
+	Time     time.Time
+	Duration time.Duration
+	Err      error
+	Reason   string
+}
+
+// Events returns a channel on which TaskEvent values are published as tasks
+// are queued, started, and finished during Run or RunContext. The channel is
+// created the first time Events is called and is buffered; if the buffer
+// fills, further events are dropped rather than blocking task execution, so
+// callers that need every event should drain the channel promptly or use
+// OnTaskStatus instead.
+func (tr *Tasker) Events() <-chan TaskEvent {
+	tr.events_mux.Lock()
+	defer tr.events_mux.Unlock()
+	if tr.events == nil {
+		tr.events = make(chan TaskEvent, 256)
+	}
+	return tr.events
+}
+
+// OnTaskStatus registers fn to be called, synchronously and in task order,
+// for every TaskEvent published during Run or RunContext. fn must not block;
+// a slow fn delays the delivery of later events to other hooks and to the
+// Events channel.
+func (tr *Tasker) OnTaskStatus(fn func(TaskEvent)) {
+	tr.events_mux.Lock()
+	defer tr.events_mux.Unlock()
+	tr.hooks = append(tr.hooks, fn)
+}
+
+// emit publishes ev to every hook registered via OnTaskStatus and, if Events
+// has been called, to the events channel. It never blocks on the channel.
+//
+// events_mux is held for the whole call, not just while reading tr.hooks, so
+// that concurrently finishing tasks can't interleave their hook calls: this
+// is what makes the "synchronously and in task order" guarantee on
+// OnTaskStatus true under the parallelism cap, not just documented.
+func (tr *Tasker) emit(ev TaskEvent) {
+	tr.events_mux.Lock()
+	defer tr.events_mux.Unlock()
+
+	for _, hook := range tr.hooks {
+		hook(ev)
+	}
+	if tr.events != nil {
+		select {
+		case tr.events <- ev:
+		default:
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package tasker
+
+import (
+	"testing"
+)
+
+func TestEventsReportsLifecycle(t *testing.T) {
+	tr, err := NewTasker(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Add("dep", nil, good_task); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Add("foo", []string{"dep"}, good_task); err != nil {
+		t.Fatal(err)
+	}
+
+	events := tr.Events()
+
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string][]TaskEventType)
+	for i := 0; i < 6; i++ {
+		ev := <-events
+		seen[ev.Task] = append(seen[ev.Task], ev.Type)
+	}
+
+	want := []TaskEventType{EventQueued, EventStarted, EventSucceeded}
+	for _, name := range []string{"dep", "foo"} {
+		types := seen[name]
+		if len(types) != len(want) {
+			t.Fatalf("%s: unexpected event sequence %v", name, types)
+		}
+		for i, ty := range want {
+			if types[i] != ty {
+				t.Errorf("%s: event %d = %s, want %s", name, i, types[i], ty)
+			}
+		}
+	}
+}
+
+func TestOnTaskStatusReportsFailure(t *testing.T) {
+	tr, err := NewTasker(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Add("foo", nil, bad_task); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []TaskEvent
+	tr.OnTaskStatus(func(ev TaskEvent) {
+		got = append(got, ev)
+	})
+
+	if err := tr.Run(); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if len(got) != 3 || got[0].Type != EventQueued || got[1].Type != EventStarted || got[2].Type != EventFailed {
+		t.Fatalf("unexpected events: %v", got)
+	}
+	if got[2].Err == nil {
+		t.Error("expected Err to be set on EventFailed")
+	}
+}
+
+// TestOnTaskStatusSerializedAcrossConcurrentTasks guards against a hook
+// being invoked concurrently by two tasks finishing at the same time under
+// the parallelism cap, which would violate OnTaskStatus's "synchronously and
+// in task order" guarantee. A plain append (the obvious thing to write
+// given that doc comment) is safe here only if emit serializes hook calls;
+// run with -race.
+func TestOnTaskStatusSerializedAcrossConcurrentTasks(t *testing.T) {
+	tr, err := NewTasker(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if err := tr.Add(name, nil, good_task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []TaskEvent
+	tr.OnTaskStatus(func(ev TaskEvent) {
+		got = append(got, ev)
+	})
+
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 12 {
+		t.Fatalf("expected 12 events for 4 tasks, got %d", len(got))
+	}
+}
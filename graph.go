@@ -0,0 +1,137 @@
+package tasker
+
+import (
+	"context"
+)
+
+// Graph returns a copy of the dependency graph: a map from each added task's
+// name to the names of the tasks it depends on.
+func (tr *Tasker) Graph() map[string][]string {
+	graph := make(map[string][]string, len(tr.dep_graph))
+	for name, deps := range tr.dep_graph {
+		graph[name] = append([]string(nil), deps...)
+	}
+	return graph
+}
+
+// ReverseGraph returns a map from each added task's name to the names of the
+// tasks that directly depend on it, i.e. the dependency graph with every
+// edge reversed.
+func (tr *Tasker) ReverseGraph() map[string][]string {
+	return tr.reverse_graph()
+}
+
+func (tr *Tasker) reverse_graph() map[string][]string {
+	reverse := make(map[string][]string, len(tr.dep_graph))
+	for name := range tr.dep_graph {
+		if _, ok := reverse[name]; !ok {
+			reverse[name] = nil
+		}
+		for _, dep := range tr.dep_graph[name] {
+			reverse[dep] = append(reverse[dep], name)
+		}
+	}
+	return reverse
+}
+
+// Plan returns the names of the tasks that RunUpstream(names...) (or, for
+// the default set, Run()) would execute, in an order consistent with their
+// dependencies. It does not run anything. An error is returned under the
+// same conditions as Run: an unknown name, a missing dependency, or a cycle.
+func (tr *Tasker) Plan(names... string) ([]string, error) {
+	names, err := tr.resolve_names(names)
+	if err != nil {
+		return nil, err
+	}
+	subset := tr.subgraph_for(names)
+	for name := range subset {
+		if !tr.needs_rerun(name) {
+			delete(subset, name)
+		}
+	}
+	return tr.topo_order(subset), nil
+}
+
+// topo_order returns the tasks in subset in an order consistent with
+// dep_graph restricted to subset, using Kahn's algorithm.
+func (tr *Tasker) topo_order(subset map[string]bool) []string {
+	reverse_deps := make(map[string][]string, len(subset))
+	pending := make(map[string]int, len(subset))
+	var ready []string
+	for name := range subset {
+		for _, dep := range tr.dep_graph[name] {
+			if subset[dep] {
+				pending[name]++
+				reverse_deps[dep] = append(reverse_deps[dep], name)
+			}
+		}
+		if pending[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	order := make([]string, 0, len(subset))
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+
+		for _, dependent := range reverse_deps[name] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	return order
+}
+
+// downstream_subgraph_for returns names together with every task that
+// transitively depends on one of them.
+func (tr *Tasker) downstream_subgraph_for(names []string) map[string]bool {
+	reverse := tr.reverse_graph()
+
+	subset := make(map[string]bool)
+	var visit func(string)
+	visit = func(name string) {
+		if subset[name] {
+			return
+		}
+		subset[name] = true
+		for _, dependent := range reverse[name] {
+			visit(dependent)
+		}
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return subset
+}
+
+// RunUpstream runs names and their transitive dependencies, exactly as Run
+// does; it formalizes and documents Run's existing selection behavior under
+// a name that makes the selection explicit. If no tasks are provided, every
+// added task is run, as with Run.
+func (tr *Tasker) RunUpstream(names... string) error {
+	return tr.Run(names...)
+}
+
+// RunDownstream runs names together with every task that transitively
+// depends on one of them, leaving any task outside that set unrun. This is
+// useful for rebuilding everything affected by a change to names without
+// rerunning the rest of the graph.
+//
+// A dependency of a selected task that is itself not selected is treated as
+// already satisfied, so RunDownstream does not pull in upstream tasks the
+// way Run and RunUpstream do; it returns an UnsatisfiedDepError instead if
+// such a dependency has not actually completed successfully in an earlier
+// Run, RunContext, RunUpstream or RunDownstream call.
+//
+// RunDownstream may be called more than once, exactly like Run.
+func (tr *Tasker) RunDownstream(names... string) error {
+	names, err := tr.resolve_names(names)
+	if err != nil {
+		return err
+	}
+	return tr.runTasksIn(context.Background(), tr.downstream_subgraph_for(names))
+}
@@ -0,0 +1,162 @@
+package tasker
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// add_diamond adds a small diamond-shaped dependency graph (base, depended
+// on by left and right, depended on by top) to tr. called records which
+// tasks ran, guarded by a mutex since left and right may run concurrently.
+func add_diamond(t *testing.T, tr *Tasker, called map[string]bool) {
+	var mux sync.Mutex
+	add := func(name string, deps []string) {
+		n := name
+		if err := tr.Add(n, deps, func() error {
+			mux.Lock()
+			called[n] = true
+			mux.Unlock()
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("base", nil)
+	add("left", []string{"base"})
+	add("right", []string{"base"})
+	add("top", []string{"left", "right"})
+}
+
+func TestPlanOrdersByDependency(t *testing.T) {
+	tr, err := NewTasker(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	add_diamond(t, tr, make(map[string]bool))
+
+	plan, err := tr.Plan("top")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := make(map[string]int, len(plan))
+	for i, name := range plan {
+		index[name] = i
+	}
+
+	if len(plan) != 4 {
+		t.Fatalf("expected 4 tasks in the plan, got %v", plan)
+	}
+	if index["base"] > index["left"] || index["base"] > index["right"] {
+		t.Errorf("base must come before left and right: %v", plan)
+	}
+	if index["left"] > index["top"] || index["right"] > index["top"] {
+		t.Errorf("left and right must come before top: %v", plan)
+	}
+}
+
+func TestPlanDoesNotRunAnything(t *testing.T) {
+	tr, err := NewTasker(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	called := make(map[string]bool)
+	add_diamond(t, tr, called)
+
+	if _, err := tr.Plan("top"); err != nil {
+		t.Fatal(err)
+	}
+	if len(called) != 0 {
+		t.Errorf("Plan ran tasks: %v", called)
+	}
+
+	// Plan must not prevent a later Run.
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunDownstreamRunsOnlyDependents(t *testing.T) {
+	tr, err := NewTasker(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	called := make(map[string]bool)
+	add_diamond(t, tr, called)
+
+	// base and right, which top also depends on, must have already
+	// completed before a downstream selection can treat them as
+	// satisfied: run the whole graph once first.
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for name := range called {
+		delete(called, name)
+	}
+
+	if err := tr.Invalidate("left", "top"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.RunDownstream("left"); err != nil {
+		t.Fatal(err)
+	}
+
+	if called["base"] {
+		t.Error("base is upstream of left and should not have rerun")
+	}
+	if !called["left"] || !called["top"] {
+		t.Errorf("left and its dependent top should have rerun: %v", called)
+	}
+	if called["right"] {
+		t.Error("right does not depend on left and should not have rerun")
+	}
+}
+
+// TestRunDownstreamErrorsOnUnsatisfiedSiblingDep guards the invariant a
+// Tasker exists to provide: a task never starts until every dependency it
+// was Added with has actually completed. top depends on both left and
+// right; selecting "left" alone on a brand-new Tasker must not run top,
+// since its other dependency, right, has never run.
+func TestRunDownstreamErrorsOnUnsatisfiedSiblingDep(t *testing.T) {
+	tr, err := NewTasker(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	called := make(map[string]bool)
+	add_diamond(t, tr, called)
+
+	err = tr.RunDownstream("left")
+	var ude *UnsatisfiedDepError
+	if !errors.As(err, &ude) {
+		t.Fatalf("expected an UnsatisfiedDepError, got %v", err)
+	}
+	if len(called) != 0 {
+		t.Errorf("RunDownstream ran tasks despite an unsatisfied dependency: %v", called)
+	}
+}
+
+func TestGraphAndReverseGraph(t *testing.T) {
+	tr, err := NewTasker(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	add_diamond(t, tr, make(map[string]bool))
+
+	graph := tr.Graph()
+	sort.Strings(graph["top"])
+	if !reflect.DeepEqual(graph["top"], []string{"left", "right"}) {
+		t.Errorf("unexpected Graph()[\"top\"]: %v", graph["top"])
+	}
+
+	reverse := tr.ReverseGraph()
+	sort.Strings(reverse["base"])
+	if !reflect.DeepEqual(reverse["base"], []string{"left", "right"}) {
+		t.Errorf("unexpected ReverseGraph()[\"base\"]: %v", reverse["base"])
+	}
+	if len(reverse["top"]) != 0 {
+		t.Errorf("top has no dependents, got %v", reverse["top"])
+	}
+}
@@ -0,0 +1,119 @@
+package tasker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Reset clears the recorded result of every task added so far, as if none of
+// them had ever run, without removing any task or its dependency graph, and
+// lets a Tasker be Run again from scratch. Unlike Invalidate, it also forgets
+// that tasks completed, so a later RunDownstream treats none of them as an
+// already satisfied dependency.
+func (tr *Tasker) Reset() {
+	for _, ti := range tr.tis {
+		ti.done = false
+		ti.err = nil
+	}
+	tr.invalidated = nil
+}
+
+// Invalidate marks names, or every added task if names is empty, to actually
+// rerun on the next call to Run, RunContext or RunDownstream, even if it
+// already completed successfully and is not a CacheableTask.
+//
+// An error is returned if any name has not been added.
+func (tr *Tasker) Invalidate(names... string) error {
+	if len(names) == 0 {
+		names = make([]string, 0, len(tr.tis))
+		for name := range tr.tis {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		if _, ok := tr.tis[name]; !ok {
+			return fmt.Errorf("tasker: task not found: %s", name)
+		}
+	}
+
+	if tr.invalidated == nil {
+		tr.invalidated = make(map[string]bool, len(names))
+	}
+	for _, name := range names {
+		tr.invalidated[name] = true
+	}
+	return nil
+}
+
+// Remove deletes the task named name, along with its recorded result. An
+// error is returned if name has not been added, or if another task still
+// lists it as a dependency; use RemoveCascade to remove those dependents
+// too.
+func (tr *Tasker) Remove(name string) error {
+	if _, ok := tr.tis[name]; !ok {
+		return fmt.Errorf("tasker: task not found: %s", name)
+	}
+	if dependents := tr.reverse_graph()[name]; len(dependents) > 0 {
+		return fmt.Errorf("tasker: cannot remove %s: required by %v", name, dependents)
+	}
+	tr.remove(name)
+	return nil
+}
+
+// RemoveCascade deletes the task named name together with every task that
+// transitively depends on it. An error is returned if name has not been
+// added.
+func (tr *Tasker) RemoveCascade(name string) error {
+	if _, ok := tr.tis[name]; !ok {
+		return fmt.Errorf("tasker: task not found: %s", name)
+	}
+	for dependent := range tr.downstream_subgraph_for([]string{name}) {
+		tr.remove(dependent)
+	}
+	return nil
+}
+
+// remove deletes name's task_info, dependency list and invalidation mark.
+func (tr *Tasker) remove(name string) {
+	delete(tr.tis, name)
+	delete(tr.dep_graph, name)
+	delete(tr.invalidated, name)
+}
+
+// Replace updates the task named name in place, as if it had been Remove'd
+// and Added again with deps and task, and marks name and every task that
+// transitively depends on it to rerun on the next call to Run, RunContext or
+// RunDownstream.
+//
+// An error is returned if name has not already been added; use Add for a new
+// task.
+func (tr *Tasker) Replace(name string, deps []string, task Task) error {
+	ti, ok := tr.tis[name]
+	if !ok {
+		return fmt.Errorf("tasker: task not found: %s", name)
+	}
+	for _, dep := range deps {
+		if name == dep {
+			return errors.New("task must not add itself as a dependency")
+		}
+	}
+
+	ti.ctx_task = func(ctx context.Context) error {
+		return task()
+	}
+	ti.opts = TaskOptions{}
+	ti.cacheable = false
+	ti.done = false
+	ti.err = nil
+	ti.output = nil
+	ti.has_output = false
+	tr.dep_graph[name] = deps
+
+	downstream := tr.downstream_subgraph_for([]string{name})
+	names := make([]string, 0, len(downstream))
+	for n := range downstream {
+		names = append(names, n)
+	}
+	return tr.Invalidate(names...)
+}
@@ -0,0 +1,129 @@
+package tasker
+
+import (
+	"testing"
+)
+
+func TestInvalidateForcesRerun(t *testing.T) {
+	tr, err := NewTasker(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := new_good_test_task("foo", nil)
+	if err := tt.add(tr); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	tt.called = false
+	if err := tr.Invalidate("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !tt.called {
+		t.Error("foo did not rerun after being invalidated")
+	}
+}
+
+func TestResetRerunsEverything(t *testing.T) {
+	tr, err := NewTasker(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tts := []*test_task{
+		new_good_test_task("base", nil),
+		new_good_test_task("top", []string{"base"}),
+	}
+	for _, tt := range tts {
+		if err := tt.add(tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tts {
+		tt.called = false
+	}
+	tr.Reset()
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tts {
+		if !tt.called {
+			t.Errorf("%s did not rerun after Reset", tt.name)
+		}
+	}
+}
+
+func TestRemoveFailsWithDependent(t *testing.T) {
+	tr, err := NewTasker(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Add("base", nil, good_task); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Add("top", []string{"base"}, good_task); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tr.Remove("base"); err == nil {
+		t.Fatal("expected an error removing a task with a dependent")
+	}
+	if err := tr.RemoveCascade("base"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Add("base", nil, good_task); err != nil {
+		t.Fatalf("base should be free to re-add after RemoveCascade: %v", err)
+	}
+	if _, ok := tr.tis["top"]; ok {
+		t.Error("top should have been removed by RemoveCascade")
+	}
+}
+
+func TestReplaceRerunsTaskAndDependents(t *testing.T) {
+	tr, err := NewTasker(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base_calls := 0
+	if err := tr.Add("base", nil, func() error {
+		base_calls++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	top_calls := 0
+	if err := tr.Add("top", []string{"base"}, func() error {
+		top_calls++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tr.Replace("base", nil, func() error {
+		base_calls++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if base_calls != 2 {
+		t.Errorf("base should have rerun after Replace, called %d times", base_calls)
+	}
+	if top_calls != 2 {
+		t.Errorf("top should have rerun after its dependency was replaced, called %d times", top_calls)
+	}
+}
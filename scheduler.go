@@ -0,0 +1,282 @@
+package tasker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// scheduler runs the tasks in a dependency subgraph using a fixed pool of
+// workers, bounded by the Tasker's semaphore.
+//
+// This replaces the earlier design, which spawned a goroutine per
+// (task, dependent) edge and had each goroutine recursively spawn its
+// dependencies' goroutines before blocking on the Tasker's semaphore. That
+// scaled to O(E) goroutines and could deadlock when the semaphore was
+// smaller than the graph's depth: a worker holding the one free slot could
+// block waiting on a dependency goroutine that itself could never acquire a
+// slot. The scheduler instead computes indegrees up front and only ever
+// dispatches a task once every dependency has finished, so a worker is never
+// blocked on work nobody has handed to a worker yet, and the goroutine count
+// never exceeds the semaphore size.
+type scheduler struct {
+	tr  *Tasker
+	ctx context.Context
+
+	// reverse_deps[d] lists the tasks in the subgraph that directly
+	// depend on d.
+	reverse_deps map[string][]string
+
+	mux       sync.Mutex
+	pending   map[string]int // remaining unfinished deps, by task name.
+	remaining int            // tasks in the subgraph not yet finished.
+	last_err  error
+
+	ready    chan string
+	finished chan struct{}
+}
+
+// enqueue publishes an EventQueued for name and hands it to a worker.
+func (s *scheduler) enqueue(name string) {
+	s.tr.emit(TaskEvent{Task: name, Type: EventQueued, Time: time.Now()})
+	s.ready <- name
+}
+
+// work pulls ready task names and runs them until the subgraph is drained.
+func (s *scheduler) work() {
+	for name := range s.ready {
+		s.run(name)
+	}
+}
+
+// run executes (or, if ctx is already done, skips) the task named name, then
+// finalizes it.
+func (s *scheduler) run(name string) {
+	ti := s.tr.tis[name]
+	var err error
+
+	select {
+	case <-s.ctx.Done():
+		err = ErrCanceled
+		s.tr.emit(TaskEvent{Task: name, Type: EventSkipped, Time: time.Now(), Reason: "canceled"})
+	default:
+		if w_err := s.tr.wait(s.ctx); w_err != nil {
+			err = w_err
+			s.tr.emit(TaskEvent{Task: name, Type: EventSkipped, Time: time.Now(), Reason: "canceled"})
+		} else {
+			started := time.Now()
+			s.tr.emit(TaskEvent{Task: name, Type: EventStarted, Time: started})
+
+			err = s.tr.runTaskFunc(s.ctx, ti)
+			s.tr.signal()
+
+			ev := TaskEvent{Task: name, Time: time.Now(), Duration: time.Since(started)}
+			if err != nil {
+				ev.Type = EventFailed
+				ev.Err = err
+			} else {
+				ev.Type = EventSucceeded
+			}
+			s.tr.emit(ev)
+		}
+	}
+
+	ti.done = true
+	ti.err = err
+	s.finish(name, err)
+}
+
+// finish records that name has finished with err and propagates to its
+// dependents: on success, it decrements their pending count and enqueues any
+// that have become ready; on failure (including cancellation), it skips
+// every transitive dependent with the same error, without ever running them.
+func (s *scheduler) finish(name string, err error) {
+	if s.account_for(err) {
+		return
+	}
+
+	if err != nil {
+		s.skip(name, err)
+		return
+	}
+
+	for _, dependent := range s.reverse_deps[name] {
+		s.mux.Lock()
+		s.pending[dependent]--
+		ready := s.pending[dependent] == 0
+		s.mux.Unlock()
+		if ready {
+			s.enqueue(dependent)
+		}
+	}
+}
+
+// skip marks every direct and transitive dependent of name as done and
+// failed with err, without running any of them, since one of their
+// dependencies (transitively) failed or was canceled.
+func (s *scheduler) skip(name string, err error) {
+	for _, dependent := range s.reverse_deps[name] {
+		ti := s.tr.tis[dependent]
+
+		s.mux.Lock()
+		already_done := ti.done
+		ti.done = true
+		s.mux.Unlock()
+		if already_done {
+			continue
+		}
+
+		ti.err = err
+		s.tr.emit(TaskEvent{Task: dependent, Type: EventQueued, Time: time.Now()})
+		s.tr.emit(TaskEvent{
+			Task:   dependent,
+			Type:   EventSkipped,
+			Time:   time.Now(),
+			Reason: fmt.Sprintf("dependency failed: %v", err),
+		})
+
+		if s.account_for(err) {
+			return
+		}
+		s.skip(dependent, err)
+	}
+}
+
+// account_for records that one more task in the subgraph has finished with
+// err, and reports whether that was the last one.
+func (s *scheduler) account_for(err error) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.remaining--
+	if err != nil && s.last_err == nil {
+		s.last_err = err
+	}
+
+	if s.remaining == 0 {
+		close(s.ready)
+		close(s.finished)
+		return true
+	}
+	return false
+}
+
+// subgraph_for returns the set of tasks reachable from names (inclusive) by
+// following dep_graph, i.e. the tasks that must run in order to run names.
+func (tr *Tasker) subgraph_for(names []string) map[string]bool {
+	subset := make(map[string]bool)
+	var visit func(string)
+	visit = func(name string) {
+		if subset[name] {
+			return
+		}
+		subset[name] = true
+		for _, dep := range tr.dep_graph[name] {
+			visit(dep)
+		}
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return subset
+}
+
+// runTasks runs the subgraph of tasks reachable from names using a
+// scheduler and waits for them to finish. The last error encountered, if
+// any, is returned.
+func (tr *Tasker) runTasks(ctx context.Context, names... string) error {
+	return tr.runTasksIn(ctx, tr.subgraph_for(names))
+}
+
+// needs_rerun reports whether the task named name must actually run on this
+// call: it never has, it failed last time, it was added with AddCacheable
+// (whose own Cache lookup decides whether to redo the work), or it was
+// marked with Invalidate. A task for which this is false is treated as
+// already satisfied by its previous result.
+func (tr *Tasker) needs_rerun(name string) bool {
+	ti := tr.tis[name]
+	return !ti.done || ti.err != nil || ti.cacheable || tr.invalidated[name]
+}
+
+// runTasksIn runs exactly the tasks in subset that need_rerun using a
+// scheduler and waits for them to finish. A task in subset that does not
+// need to rerun is treated as already satisfied and is not waited on; a
+// dependency of a task in subset that is itself not in subset is handled the
+// same way, but only if it has actually completed successfully already -
+// otherwise there is nothing to treat as satisfied, and an
+// UnsatisfiedDepError is returned instead. This lets Run, RunContext and
+// RunDownstream all be called more than once on the same Tasker, only
+// redoing the work that actually changed. The last error encountered, if
+// any, is returned.
+func (tr *Tasker) runTasksIn(ctx context.Context, subset map[string]bool) error {
+	active := make(map[string]bool, len(subset))
+	for name := range subset {
+		if tr.needs_rerun(name) {
+			active[name] = true
+			delete(tr.invalidated, name)
+		} else {
+			tr.emit(TaskEvent{Task: name, Type: EventSkipped, Time: time.Now(), Reason: "unchanged since previous run"})
+		}
+	}
+
+	for name := range active {
+		for _, dep := range tr.dep_graph[name] {
+			if subset[dep] {
+				continue
+			}
+			dep_ti, ok := tr.tis[dep]
+			if !ok || !dep_ti.done || dep_ti.err != nil {
+				return NewUnsatisfiedDepError(name, dep)
+			}
+		}
+	}
+
+	if len(active) == 0 {
+		return nil
+	}
+
+	reverse_deps := make(map[string][]string, len(active))
+	pending := make(map[string]int, len(active))
+	for name := range active {
+		// A task rerun on this call must not look finished from an earlier
+		// call, or skip would pass over it without accounting for it.
+		tr.tis[name].done = false
+		for _, dep := range tr.dep_graph[name] {
+			if active[dep] {
+				pending[name]++
+				reverse_deps[dep] = append(reverse_deps[dep], name)
+			}
+		}
+	}
+
+	s := &scheduler{
+		tr:           tr,
+		ctx:          ctx,
+		reverse_deps: reverse_deps,
+		pending:      pending,
+		remaining:    len(active),
+		ready:        make(chan string, len(active)),
+		finished:     make(chan struct{}),
+	}
+
+	// Seed the ready queue before starting any workers: once a worker can
+	// run, it may concurrently mutate pending through finish, so nothing
+	// else may read or write it afterward.
+	for name := range active {
+		if pending[name] == 0 {
+			s.enqueue(name)
+		}
+	}
+
+	workers := len(active)
+	if tr.semaphore != nil && cap(tr.semaphore) < workers {
+		workers = cap(tr.semaphore)
+	}
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+
+	<-s.finished
+	return s.last_err
+}
@@ -0,0 +1,58 @@
+package tasker
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestRunDeepChainWithSmallParallelism exercises a dependency chain deeper
+// than the parallelism cap, which deadlocked the old goroutine-per-edge
+// scheduler: a worker holding the one semaphore slot would block on a
+// dependency goroutine that could never acquire it.
+func TestRunDeepChainWithSmallParallelism(t *testing.T) {
+	tr, err := NewTasker(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const depth = 50
+	for i := 0; i < depth; i++ {
+		var deps []string
+		if i > 0 {
+			deps = []string{fmt.Sprintf("t%d", i-1)}
+		}
+		if err := tr.Add(fmt.Sprintf("t%d", i), deps, good_task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunNamedTargetsSkipsUnrelatedRoots(t *testing.T) {
+	tr, err := NewTasker(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wanted := new_good_test_task("wanted", nil)
+	unrelated := new_good_test_task("unrelated", nil)
+	if err := wanted.add(tr); err != nil {
+		t.Fatal(err)
+	}
+	if err := unrelated.add(tr); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tr.Run("wanted"); err != nil {
+		t.Fatal(err)
+	}
+	if !wanted.called {
+		t.Error("wanted was not called")
+	}
+	if unrelated.called {
+		t.Error("unrelated should not have been called")
+	}
+}
@@ -1,33 +1,5 @@
 package tasker
 
-import (
-	"errors"
-)
-
-type string_stack struct {
-	stack []string
-	count int
-}
-
-func (ss *string_stack) push(e string) {
-	ss.stack = append(ss.stack, e)
-	ss.count++
-}
-
-func (ss *string_stack) pop() (string, error) {
-	if ss.count == 0 {
-		return "", errors.New("Stack is empty")
-	}
-	ss.count--
-	e := ss.stack[ss.count]
-	ss.stack = ss.stack[:ss.count]
-	return e, nil
-}
-
-func new_string_stack() *string_stack {
-	return &string_stack{make([]string, 0), 0}
-}
-
 type tarjan_info struct {
 	graph map[string][]string
 
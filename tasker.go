@@ -1,62 +1,53 @@
 package tasker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 )
 
-type CycleError [][]string
-
-func (ce CycleError) Error() string {
-	msg := "tasker: "
-	if len(ce) > 1 {
-		msg += "cycles"
-	} else {
-		msg += "cycle"
-	}
-	msg += " detected: "
-	for i, c := range ce {
-		for j, e := range c {
-			msg += e
-			if j < len(c)-1 {
-				msg += " -> "
-			}
-		}
-		if i < len(ce)-1 {
-			msg += ", "
-		}
-	}
-	return msg
-}
-
+// A Task is a function called with no arguments that returns an error. If
+// variable information is required, consider providing a closure.
+type Task func() error
 
-type DepNotFoundError struct {
-	v string
-	w string
-}
+// A ContextTask is a Task that receives a context.Context. It must return
+// promptly once ctx is done; tasks added with a Timeout or that are running
+// when Run's context is canceled are expected to observe ctx.Done().
+type ContextTask func(ctx context.Context) error
 
-func NewDepNotFoundError(v, w string) *DepNotFoundError {
-	return &DepNotFoundError{v, w}
+// RetryPolicy describes how a failing task should be retried.
+//
+// MaxAttempts is the total number of times the task is run, including the
+// first attempt; a value less than 2 means no retries. Backoff is the delay
+// between attempts.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
 }
 
-func (dnfe *DepNotFoundError) Error() string {
-	return fmt.Sprintf("tasker: %s not found, required by %s", dnfe.w, dnfe.v)
+// TaskOptions carries the optional, per-task settings accepted by
+// AddWithOptions.
+//
+// Timeout, if positive, bounds how long a single attempt of the task may run;
+// the context.Context passed to the task is canceled once it elapses. Retry,
+// if non-nil, is applied when the task returns an error.
+type TaskOptions struct {
+	Timeout time.Duration
+	Retry   *RetryPolicy
 }
 
-
-// A Task is a function called with no arguments that returns an error. If
-// variable information is required, consider providing a closure.
-type Task func() error
-
-
 // task info holds run-time information related to a task identified by
 // task_info.name.
 type task_info struct {
-	task Task        // The task itself.
-	done bool        // Prevents running a task more than once.
-	err  error       // Stores error on failure.
-	mux  *sync.Mutex // Controls access to this task.
+	ctx_task  ContextTask // The task, aware of the Run/RunContext context.
+	opts      TaskOptions // Per-task timeout and retry settings.
+	done       bool        // Set once the scheduler has finished or skipped this task.
+	err        error       // Stores error on failure.
+	output     TaskOutput  // Set by a CacheableTask; read by Output.
+	has_output bool        // Whether output has actually been published; see Output.
+	cacheable  bool        // Set by AddCacheable; see Tasker.needs_rerun.
 
 	// Elements used in cycle detection.
 	index    int
@@ -64,16 +55,8 @@ type task_info struct {
 	on_stack bool
 }
 
-func (ti *task_info) lock() {
-	ti.mux.Lock()
-}
-
-func (ti *task_info) unlock() {
-	ti.mux.Unlock()
-}
-
-func new_task_info(task Task) *task_info {
-	return &task_info{task, false, nil, &sync.Mutex{}, -1, -1, false}
+func new_task_info(task ContextTask, opts TaskOptions) *task_info {
+	return &task_info{task, opts, false, nil, nil, false, false, -1, -1, false}
 }
 
 
@@ -93,14 +76,32 @@ type Tasker struct {
 	stack *string_stack
 	cycles [][]string
 
-	// Indicates whether Run has been called.
-	was_run bool
+	// Elements used to publish TaskEvents. See Events, OnTaskStatus and
+	// emit.
+	events_mux sync.Mutex
+	events     chan TaskEvent
+	hooks      []func(TaskEvent)
+
+	// cache is consulted and populated by tasks added through
+	// AddCacheable. It is nil unless UseCache has been called.
+	cache Cache
+
+	// invalidated holds the names marked by Invalidate to actually rerun
+	// on the next Run, RunContext or RunDownstream call. See needs_rerun.
+	invalidated map[string]bool
 }
 
-// wait signals that a task is running and blocks until it may be run.
-func (tr *Tasker) wait() {
-	if tr.semaphore != nil {
-		tr.semaphore <- true
+// wait signals that a task is running and blocks until it may be run, or
+// until ctx is done, whichever happens first.
+func (tr *Tasker) wait(ctx context.Context) error {
+	if tr.semaphore == nil {
+		return nil
+	}
+	select {
+	case tr.semaphore <- true:
+		return nil
+	case <-ctx.Done():
+		return ErrCanceled
 	}
 }
 
@@ -132,7 +133,11 @@ func NewTasker(n int) (*Tasker, error) {
 		-1,
 		new_string_stack(),
 		make([][]string, 0),
-		false,
+		sync.Mutex{},
+		nil,
+		nil,
+		nil,
+		nil,
 	}
 	return tr, nil
 }
@@ -147,6 +152,18 @@ func NewTasker(n int) (*Tasker, error) {
 //
 // An error is returned if name is not unique.
 func (tr *Tasker) Add(name string, deps []string, task Task) error {
+	return tr.AddWithOptions(name, deps, func(ctx context.Context) error {
+		return task()
+	}, TaskOptions{})
+}
+
+// AddWithOptions is like Add, but registers a ContextTask together with
+// TaskOptions controlling its per-task Timeout and Retry policy.
+//
+// The context passed to task is derived from the context.Context given to
+// RunContext (or context.Background(), for Run), and is canceled early if
+// Timeout elapses.
+func (tr *Tasker) AddWithOptions(name string, deps []string, task ContextTask, opts TaskOptions) error {
 	if name == "" {
 		return errors.New("name is empty")
 	}
@@ -161,7 +178,7 @@ func (tr *Tasker) Add(name string, deps []string, task Task) error {
 		}
 	}
 
-	tr.tis[name] = new_task_info(task)
+	tr.tis[name] = new_task_info(task, opts)
 	tr.dep_graph[name] = deps
 	return nil
 }
@@ -203,7 +220,12 @@ func (tr *Tasker) find_cycles(v string) {
 
 		// Recursively consider dependencies of v.
 		for _, w := range tr.dep_graph[v] {
-			w_ti := tr.tis[w]
+			w_ti, ok := tr.tis[w]
+			if !ok {
+				// w is a missing dependency, reported separately by
+				// verify; it cannot be part of a cycle.
+				continue
+			}
 			if w_ti.index == -1 {
 
 				// w has not yet been visited.
@@ -256,130 +278,156 @@ func (tr *Tasker) find_cycles(v string) {
 	}
 }
 
-// verify returns an error if any task dependencies haven't been added or any
-// cycles exist among the tasks.
+// verify returns a *ValidationError reporting every missing task dependency
+// and every cycle among the tasks, or nil if there are none.
 func (tr *Tasker) verify() error {
+	var errs []error
+
 	for name, deps := range tr.dep_graph {
 		for _, dep := range deps {
 			if _, ok := tr.tis[dep]; !ok {
-				return NewDepNotFoundError(name, dep)
+				errs = append(errs, NewDepNotFoundError(name, dep))
 			}
 		}
 	}
+
 	tr.find_cycles("")
-	if len(tr.cycles) > 0 {
-		return CycleError(tr.cycles)
+	for _, scc := range tr.cycles {
+		errs = append(errs, tr.cycle_path(scc))
 	}
 
-	return nil
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{errs}
 }
 
-// runTask is called recursivley as a goroutine to run tasks in parallel. It
-// runs all dependencies before running the provided task. The first error it
-// encounters will be send through err_ch, be it from a dependency or the task
-// itself. It will not run the provided task if any dependency fails.
-//
-// It initially takes the task's lock and sets a flag so that a task is not run
-// in any other goroutine. Other goroutines will wait for the lock, then see
-// that the task has already been executed, and return whatever error it had
-// produced.
-//
-// It further limits the number of consecutive tasks as defined by the size of
-// the Tasker's semaphore.
-func (tr *Tasker) runTask(name string, err_ch chan error) {
-	ti := tr.tis[name]
-
-	ti.lock()
-	defer ti.unlock()
-
-	// Don't run this task if it has been handled by another goroutine and send
-	// its error, which may be an error from running the task itself or from
-	// running one of its dependencies.
-	if ti.done {
-		err_ch <- ti.err
-		return
+// cycle_path orders the vertices of scc, a strongly connected component of
+// more than one task, along an actual dependency edge path that returns to
+// its starting vertex, e.g. ["a", "b", "c", "a"]. Every vertex in scc can
+// reach every other, so a depth-first search confined to scc is guaranteed
+// to find its way back to the start.
+func (tr *Tasker) cycle_path(scc []string) CycleError {
+	in_scc := make(map[string]bool, len(scc))
+	for _, v := range scc {
+		in_scc[v] = true
 	}
 
-	// Set this task to done.
-	ti.done = true
-
-	// Run all dependencies first. Do not continue with the current task if one
-	// fails. If that happens, this task will inherit its error from the first
-	// one that failed.
-	deps := tr.dep_graph[name]
-	dep_err_ch := make(chan error)
-	for _, dep := range deps {
-		go tr.runTask(dep, dep_err_ch)
-	}
-	for _ = range deps {
-		ti.err = <-dep_err_ch
+	start := scc[0]
+	path := []string{start}
+	visited := map[string]bool{start: true}
 
-		// Do not run this task if one of its dependencies fail.
-		if ti.err != nil {
-			err_ch <- ti.err
-			return
+	var dfs func(v string) bool
+	dfs = func(v string) bool {
+		for _, w := range tr.dep_graph[v] {
+			if !in_scc[w] || visited[w] && w != start {
+				continue
+			}
+			if w == start {
+				return true
+			}
+			visited[w] = true
+			path = append(path, w)
+			if dfs(w) {
+				return true
+			}
+			path = path[:len(path)-1]
 		}
+		return false
 	}
+	dfs(start)
 
-	// Limit the number of consecutive tasks.
-	tr.wait()
-	defer tr.signal()
-
-	ti.err = ti.task()
-	err_ch <- ti.err
+	return append(append(CycleError{}, path...), start)
 }
 
-// runTasks runs a list of tasks using runTask and waits for them to finish.
-func (tr *Tasker) runTasks(names... string) error {
-	err_ch := make(chan error)
-	for _, name := range names {
-		go tr.runTask(name, err_ch)
+// runTaskFunc invokes ti's ContextTask, applying its Timeout and Retry
+// options. It returns ErrCanceled if ctx is done before the task succeeds,
+// and otherwise the error from the last attempt.
+func (tr *Tasker) runTaskFunc(ctx context.Context, ti *task_info) error {
+	attempts := 1
+	var backoff time.Duration
+	if ti.opts.Retry != nil && ti.opts.Retry.MaxAttempts > attempts {
+		attempts = ti.opts.Retry.MaxAttempts
+		backoff = ti.opts.Retry.Backoff
 	}
 
-	// Wait for all tasks to finish. Return the first error encountered.
 	var err error
-	for _ = range names {
-		e := <-err_ch
+	for attempt := 1; attempt <= attempts; attempt++ {
+		task_ctx := ctx
+		var cancel context.CancelFunc
+		if ti.opts.Timeout > 0 {
+			task_ctx, cancel = context.WithTimeout(ctx, ti.opts.Timeout)
+		}
+		err = ti.ctx_task(task_ctx)
+		if cancel != nil {
+			cancel()
+		}
 		if err == nil {
-			err = e
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ErrCanceled
+		}
+		if attempt < attempts {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ErrCanceled
+			}
 		}
 	}
 	return err
 }
 
 // Run runs a list of tasks registered through Add in parallel. If not tasks
-// are provided, then all tasks are run.
+// are provided, then all tasks are run. It is equivalent to calling
+// RunContext with context.Background().
 //
-// All tasks are only run once, even if two or more other tasks depend on it.
-// A task will not run if any dependency fails.
+// Run may be called more than once on the same Tasker: a task that already
+// completed successfully is not run again unless it was added with
+// AddCacheable, whose own Cache lookup decides whether to redo the work, or
+// it was marked with Invalidate. Within a single call, all tasks are only
+// run once, even if two or more other tasks depend on it, and a task will
+// not run if any dependency fails.
 //
 // The last error from a task is returned. Otherwise, Run returns
 // nil.
 func (tr *Tasker) Run(names... string) error {
-	if tr.was_run {
-		return errors.New("tasker: already run")
+	return tr.RunContext(context.Background(), names...)
+}
+
+// RunContext is like Run, but ctx governs cancellation of the whole run. If
+// ctx is canceled, tasks that have not yet started are marked done with
+// ErrCanceled instead of running, while in-flight tasks are expected to
+// observe ctx.Done() (and any per-task Timeout) and return promptly.
+func (tr *Tasker) RunContext(ctx context.Context, names... string) error {
+	names, err := tr.resolve_names(names)
+	if err != nil {
+		return err
 	}
+	return tr.runTasks(ctx, names...)
+}
 
+// resolve_names verifies the graph (see verify) and, if names is empty,
+// returns every added task's name; otherwise it checks that every name in
+// names was added and returns names unchanged.
+func (tr *Tasker) resolve_names(names []string) ([]string, error) {
 	if err := tr.verify(); err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(names) == 0 {
-		names = make([]string, 0)
+		names = make([]string, 0, len(tr.tis))
 		for name, _ := range tr.tis {
 			names = append(names, name)
 		}
-	} else {
-		// Validate the provided tasks.
-		for _, name := range names {
-			if _, ok := tr.tis[name]; !ok {
-				return fmt.Errorf("tasker: task not found: %s", name)
-			}
-		}
+		return names, nil
 	}
 
-	// This function must not be called again at this point.
-	tr.was_run = true
-
-	return tr.runTasks(names...)
+	for _, name := range names {
+		if _, ok := tr.tis[name]; !ok {
+			return nil, fmt.Errorf("tasker: task not found: %s", name)
+		}
+	}
+	return names, nil
 }
@@ -1,8 +1,10 @@
 package tasker
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 )
 
 func good_task() error {
@@ -73,14 +75,16 @@ func test_run_task_error(t *testing.T, n int, tts[]*test_task) {
 
 func test_run_cycle_error(t *testing.T, n int, tts []*test_task) {
 	err := test_run(t, n, tts)
-	if _, ok := err.(CycleError); !ok {
+	var ce CycleError
+	if !errors.As(err, &ce) {
 		t.Fatal(err)
 	}
 }
 
 func test_run_dep_not_found_error(t *testing.T, n int, tts []*test_task) {
 	err := test_run(t, n, tts)
-	if _, ok := err.(*DepNotFoundError); !ok {
+	var dnfe *DepNotFoundError
+	if !errors.As(err, &dnfe) {
 		t.Fatal(err)
 	}
 }
@@ -170,6 +174,44 @@ func TestErrorCycleDetectionThree(t *testing.T) {
 	})
 }
 
+// TestErrorCycleReportsActualEdgePath guards against reporting a cycle in
+// arbitrary SCC pop order instead of along its real dependency edges: a, b
+// and c are added in edge order (a -> b -> c -> a), but Tarjan's SCC is
+// discovered and popped starting from whichever of the three the algorithm
+// happens to visit first (map iteration order), so the raw SCC need not
+// visit them in edge order at all. The reported CycleError must still trace
+// real edges, so the path is checked by rotating it to start at "a" rather
+// than assuming a fixed starting vertex.
+func TestErrorCycleReportsActualEdgePath(t *testing.T) {
+	err := test_run(t, 1, []*test_task{
+		new_good_test_task("a", []string{"b"}),
+		new_good_test_task("b", []string{"c"}),
+		new_good_test_task("c", []string{"a"}),
+	})
+
+	var ce CycleError
+	if !errors.As(err, &ce) {
+		t.Fatal(err)
+	}
+	if len(ce) != 4 || ce[0] != ce[3] {
+		t.Fatalf("expected a 4-element cycle returning to its start, got %v", ce)
+	}
+
+	start := 0
+	for i, name := range ce[:3] {
+		if name == "a" {
+			start = i
+			break
+		}
+	}
+	rotated := CycleError{ce[start], ce[(start+1)%3], ce[(start+2)%3], "a"}
+
+	want := CycleError{"a", "b", "c", "a"}
+	if rotated.Error() != want.Error() {
+		t.Fatalf("expected cycle path %v, got %v (raw: %v)", want, rotated, ce)
+	}
+}
+
 func TestErrorDepNotFoundOne(t *testing.T) {
 	test_run_dep_not_found_error(t, 1, []*test_task{
 		new_good_test_task("foo", []string{"bar"}),
@@ -189,25 +231,171 @@ func TestErrorDepNotFoundSome(t *testing.T) {
 	})
 }
 
-func TestErrorRunTwice(t *testing.T) {
+func TestErrorReportsAllCyclesAndMissingDeps(t *testing.T) {
+	err := test_run(t, 1, []*test_task{
+		new_good_test_task("foo", []string{"bar"}),
+		new_good_test_task("quid", []string{"pro"}),
+		new_good_test_task("pro", []string{"quo"}),
+		new_good_test_task("quo", []string{"quid"}),
+	})
+
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatal(err)
+	}
+
+	var dnfe *DepNotFoundError
+	var ce CycleError
+	var foundDepNotFound, foundCycle bool
+	for _, sub := range ve.Unwrap() {
+		if errors.As(sub, &dnfe) {
+			foundDepNotFound = true
+		}
+		if errors.As(sub, &ce) {
+			foundCycle = true
+		}
+	}
+	if !foundDepNotFound {
+		t.Errorf("expected a DepNotFoundError among %v", ve.Unwrap())
+	}
+	if !foundCycle {
+		t.Errorf("expected a CycleError among %v", ve.Unwrap())
+	}
+}
+
+func TestRunTwiceSkipsUnchangedTask(t *testing.T) {
 	tr, err := NewTasker(1)
 	if err != nil {
 		t.Fatal(err)
 	}
 	tt := new_good_test_task("foo", nil)
-	err = tt.add(tr)
+	if err := tt.add(tr); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	tt.called = false
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if tt.called {
+		t.Error("foo reran without being invalidated")
+	}
+}
+
+func TestRunContextCanceledSkipsPending(t *testing.T) {
+	tr, err := NewTasker(1)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = tr.Run()
+	if err := tr.Add("foo", nil, good_task); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = tr.RunContext(ctx, "foo")
+	if err != ErrCanceled {
+		t.Fatalf("expected ErrCanceled, got %#v", err)
+	}
+}
+
+func TestRunContextCanceledMidRunSkipsPendingDependent(t *testing.T) {
+	tr, err := NewTasker(2)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = tr.Run()
-	if err == nil {
+
+	started := make(chan struct{})
+	a := func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if err := tr.AddWithOptions("a", nil, a, TaskOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	bCalled := false
+	b := func(ctx context.Context) error {
+		bCalled = true
+		return nil
+	}
+	if err := tr.AddWithOptions("b", []string{"a"}, b, TaskOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- tr.RunContext(ctx, "a", "b")
+	}()
+
+	<-started
+	cancel()
+
+	if err := <-done; err != ErrCanceled {
+		t.Fatalf("expected ErrCanceled, got %#v", err)
+	}
+	if bCalled {
+		t.Error("b ran despite its in-flight dependency being canceled")
+	}
+	if tr.tis["b"].err != ErrCanceled {
+		t.Errorf("expected b to be marked ErrCanceled, got %#v", tr.tis["b"].err)
+	}
+}
+
+func TestAddWithOptionsRetrySucceedsAfterFailures(t *testing.T) {
+	tr, err := NewTasker(1)
+	if err != nil {
 		t.Fatal(err)
 	}
-	if err.Error() != "tasker: already run" {
+
+	attempts := 0
+	task := func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	}
+	opts := TaskOptions{Retry: &RetryPolicy{MaxAttempts: 3}}
+	if err := tr.AddWithOptions("foo", nil, task, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tr.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestAddWithOptionsTimeoutCancelsTask(t *testing.T) {
+	tr, err := NewTasker(1)
+	if err != nil {
 		t.Fatal(err)
 	}
+
+	task := func(ctx context.Context) error {
+		select {
+		case <-time.After(time.Second):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	opts := TaskOptions{Timeout: time.Millisecond}
+	if err := tr.AddWithOptions("foo", nil, task, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	err = tr.Run()
+	if err == nil {
+		t.Fatal("expected an error from the timed out task")
+	}
 }